@@ -0,0 +1,186 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// knownQuoteAssets lists quote currencies recognized when splitting a
+// concatenated symbol like "BTCUSDT" into its base and quote legs (the same
+// convention main.go registers symbols under). Checked longest-first so a
+// symbol like "ETHBTC" resolves to quote "BTC", not a shorter false match.
+var knownQuoteAssets = []string{"USDT", "USDC", "BUSD", "BTC", "ETH"}
+
+// splitSymbol splits a concatenated symbol such as "BTCUSDT" into its base
+// and quote assets, e.g. ("BTC", "USDT"). ok is false if no known quote
+// asset matches the symbol's suffix.
+func splitSymbol(symbol string) (base, quote string, ok bool) {
+	for _, q := range knownQuoteAssets {
+		if strings.HasSuffix(symbol, q) && len(symbol) > len(q) {
+			return symbol[:len(symbol)-len(q)], q, true
+		}
+	}
+	return "", "", false
+}
+
+// ArbitrageSignal reports that a triangular path's implied round-trip rate
+// cleared the watcher's configured threshold.
+type ArbitrageSignal struct {
+	Path      []string
+	NetRate   float64
+	Timestamp time.Time
+}
+
+// ArbitragePathWatcher watches a triangular path of symbols (e.g.
+// [BTCUSDT, ETHBTC, ETHUSDT]) for a round-trip conversion rate, net of
+// per-leg fees, that exceeds MinSpreadRatio above parity.
+type ArbitragePathWatcher struct {
+	engine         *MatchingEngine
+	path           []string
+	feeRatio       float64 // per-leg fee, e.g. 0.001 for 10 bps
+	minSpreadRatio float64
+	signals        chan<- ArbitrageSignal
+}
+
+// NewArbitragePathWatcher builds a watcher over path. feeRatio is deducted
+// from every leg's conversion; a signal is only emitted on Check when the
+// net round-trip rate exceeds 1+minSpreadRatio. Signals are also pushed to
+// signals, if non-nil, using a non-blocking send.
+func NewArbitragePathWatcher(engine *MatchingEngine, path []string, feeRatio, minSpreadRatio float64, signals chan<- ArbitrageSignal) *ArbitragePathWatcher {
+	return &ArbitragePathWatcher{
+		engine:         engine,
+		path:           path,
+		feeRatio:       feeRatio,
+		minSpreadRatio: minSpreadRatio,
+		signals:        signals,
+	}
+}
+
+// pathLeg is one symbol's best bid/ask plus the base/quote assets it
+// trades, resolved once per Check so both traversal directions reuse it.
+type pathLeg struct {
+	base, quote string
+	bid, ask    float64
+}
+
+// Check computes the implied round-trip rate for both directions around the
+// watcher's path — traversing its legs forward and in reverse, converting
+// the currency held after each leg into the next by buying the leg's base
+// with its quote (at the ask) or selling the base for the quote (at the
+// bid), whichever the currently-held asset requires — and reports a signal
+// if either direction closes back to its starting currency with a rate
+// clearing the configured threshold.
+func (w *ArbitragePathWatcher) Check() (ArbitrageSignal, bool) {
+	fee := 1 - w.feeRatio
+	n := len(w.path)
+	if n == 0 {
+		return ArbitrageSignal{}, false
+	}
+
+	legs := make([]pathLeg, n)
+	for i, symbol := range w.path {
+		ob, ok := w.engine.Book(symbol)
+		if !ok {
+			return ArbitrageSignal{}, false
+		}
+		bidDecimal, hasBid := ob.GetBestBid()
+		askDecimal, hasAsk := ob.GetBestAsk()
+		if !hasBid || !hasAsk {
+			return ArbitrageSignal{}, false
+		}
+		base, quote, ok := splitSymbol(symbol)
+		if !ok {
+			return ArbitrageSignal{}, false
+		}
+		bid, ask := bidDecimal.Float64(), askDecimal.Float64()
+		if bid <= 0 || ask <= 0 {
+			return ArbitrageSignal{}, false
+		}
+		legs[i] = pathLeg{base: base, quote: quote, bid: bid, ask: ask}
+	}
+
+	// traverse walks legs in the given order, converting whatever currency
+	// is held into the next leg's other asset, and reports the round-trip
+	// rate only if the walk closes back to the currency it started from.
+	traverse := func(order []int) (float64, bool) {
+		held := legs[order[0]].quote
+		start := held
+		rate := 1.0
+		for _, i := range order {
+			leg := legs[i]
+			switch held {
+			case leg.quote:
+				rate *= (1 / leg.ask) * fee
+				held = leg.base
+			case leg.base:
+				rate *= leg.bid * fee
+				held = leg.quote
+			default:
+				return 0, false
+			}
+		}
+		return rate, held == start
+	}
+
+	forwardOrder := make([]int, n)
+	backwardOrder := make([]int, n)
+	for i := range forwardOrder {
+		forwardOrder[i] = i
+		backwardOrder[i] = n - 1 - i
+	}
+
+	forwardCycle, forwardOK := traverse(forwardOrder)
+	backwardCycle, backwardOK := traverse(backwardOrder)
+	if !forwardOK && !backwardOK {
+		return ArbitrageSignal{}, false
+	}
+
+	netRate := 0.0
+	if forwardOK {
+		netRate = forwardCycle
+	}
+	if backwardOK && backwardCycle > netRate {
+		netRate = backwardCycle
+	}
+	if netRate <= 1+w.minSpreadRatio {
+		return ArbitrageSignal{}, false
+	}
+
+	signal := ArbitrageSignal{Path: w.path, NetRate: netRate, Timestamp: time.Now()}
+	if w.signals != nil {
+		select {
+		case w.signals <- signal:
+		default:
+		}
+	}
+	return signal, true
+}
+
+// Watch subscribes to depth updates from every book on the path and
+// re-evaluates Check on each update received, until stop is closed. Updates
+// are not filtered: a change at any price level triggers a Check, not only
+// one that moves the best bid or ask.
+func (w *ArbitragePathWatcher) Watch(stop <-chan struct{}) {
+	updates := make(chan DepthUpdate, 64)
+	var subscribed []*OrderBook
+	for _, symbol := range w.path {
+		if ob, ok := w.engine.Book(symbol); ok {
+			ob.Subscribe(updates)
+			subscribed = append(subscribed, ob)
+		}
+	}
+	defer func() {
+		for _, ob := range subscribed {
+			ob.Unsubscribe(updates)
+		}
+	}()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-updates:
+			w.Check()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func restOrder(ob *OrderBook, id, side string, price, qty float64) {
+	ob.AddOrder(&Order{
+		OrderID:   id,
+		Side:      side,
+		Price:     NewDecimalFromFloat(price),
+		Quantity:  NewDecimalFromFloat(qty),
+		OrderType: OrderTypeLimit,
+	})
+}
+
+func setupTriangularBooks(t *testing.T, btcusdtBid, btcusdtAsk, ethbtcBid, ethbtcAsk, ethusdtBid, ethusdtAsk float64) *MatchingEngine {
+	t.Helper()
+	engine := NewMatchingEngine()
+	btcusdt := engine.RegisterSymbol("BTCUSDT", SymbolConfig{PricePrecision: 4, QuantityPrecision: 4})
+	ethbtc := engine.RegisterSymbol("ETHBTC", SymbolConfig{PricePrecision: 6, QuantityPrecision: 4})
+	ethusdt := engine.RegisterSymbol("ETHUSDT", SymbolConfig{PricePrecision: 4, QuantityPrecision: 4})
+
+	restOrder(btcusdt, "btcusdt-bid", "buy", btcusdtBid, 10)
+	restOrder(btcusdt, "btcusdt-ask", "sell", btcusdtAsk, 10)
+	restOrder(ethbtc, "ethbtc-bid", "buy", ethbtcBid, 10)
+	restOrder(ethbtc, "ethbtc-ask", "sell", ethbtcAsk, 10)
+	restOrder(ethusdt, "ethusdt-bid", "buy", ethusdtBid, 10)
+	restOrder(ethusdt, "ethusdt-ask", "sell", ethusdtAsk, 10)
+
+	return engine
+}
+
+// TestArbitragePathWatcher_NoSignalOnUncrossedBooks guards against the
+// regression where Check multiplied the forward and backward round-trip
+// products together: since bid <= ask on every uncrossed book, that product
+// is always <= 1 and the watcher could never fire on a genuine opportunity.
+func TestArbitragePathWatcher_NoSignalOnUncrossedBooks(t *testing.T) {
+	engine := setupTriangularBooks(t, 99.99, 100.01, 0.0499, 0.0501, 4.999, 5.001)
+	defer engine.Close()
+
+	watcher := NewArbitragePathWatcher(engine, []string{"BTCUSDT", "ETHBTC", "ETHUSDT"}, 0, 0, nil)
+	if signal, fired := watcher.Check(); fired {
+		t.Fatalf("watcher fired on a tight-spread, internally consistent book: %+v", signal)
+	}
+}
+
+// TestArbitragePathWatcher_SignalsOnProfitableCycle sets up a book where
+// converting USDT -> BTC -> ETH -> USDT nets a profit, which the broken
+// forward*backward math could never detect since that product is
+// mathematically bounded by 1 regardless of input.
+func TestArbitragePathWatcher_SignalsOnProfitableCycle(t *testing.T) {
+	engine := setupTriangularBooks(t, 99, 100, 0.04, 0.05, 6000, 6001)
+	defer engine.Close()
+
+	watcher := NewArbitragePathWatcher(engine, []string{"BTCUSDT", "ETHBTC", "ETHUSDT"}, 0, 0, nil)
+	signal, fired := watcher.Check()
+	if !fired {
+		t.Fatalf("watcher failed to fire on a profitable cycle")
+	}
+	if signal.NetRate <= 1 {
+		t.Fatalf("signal NetRate = %v, want > 1", signal.NetRate)
+	}
+}
+
+// TestArbitragePathWatcher_WatchUnsubscribesOnStop guards against the
+// regression where Watch subscribed to every book on its path but never
+// unsubscribed, leaking the dead channel into ob.subscribers forever once
+// stop closed.
+func TestArbitragePathWatcher_WatchUnsubscribesOnStop(t *testing.T) {
+	engine := NewMatchingEngine()
+	defer engine.Close()
+	ob := engine.RegisterSymbol("AB", SymbolConfig{})
+
+	watcher := NewArbitragePathWatcher(engine, []string{"AB"}, 0, 0, nil)
+	stop := make(chan struct{})
+	close(stop)
+	watcher.Watch(stop)
+
+	ob.mu.RLock()
+	n := len(ob.subscribers)
+	ob.mu.RUnlock()
+	if n != 0 {
+		t.Fatalf("ob.subscribers = %d after Watch returned, want 0 (leaked subscription)", n)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// decimalScale is the fixed number of internal units per whole number
+// (10^8, akin to satoshis) used by every Decimal regardless of a symbol's
+// configured PricePrecision/QuantityPrecision. Precision is enforced
+// separately, as a tick/lot-size check, at order entry.
+const decimalScale = 100_000_000
+
+// Decimal is a fixed-point number stored as an integer multiple of
+// 1/decimalScale, so repeated partial fills never accumulate the rounding
+// drift float64 subtraction does, and equality comparisons (e.g. "is this
+// order fully filled?") are exact.
+type Decimal struct {
+	scaled int64
+}
+
+// NewDecimalFromFloat builds a Decimal from a float64, rounding to the
+// nearest internal unit. Intended for literals and display conversions,
+// not for chained arithmetic.
+func NewDecimalFromFloat(f float64) Decimal {
+	return Decimal{scaled: int64(math.Round(f * decimalScale))}
+}
+
+// DecimalFromInt builds a Decimal representing a whole number.
+func DecimalFromInt(n int64) Decimal {
+	return Decimal{scaled: n * decimalScale}
+}
+
+// Float64 converts back to a float64, e.g. for display or for math (fee
+// ratios, arbitrage rates) that doesn't require exactness.
+func (d Decimal) Float64() float64 {
+	return float64(d.scaled) / decimalScale
+}
+
+func (d Decimal) Add(o Decimal) Decimal { return Decimal{scaled: d.scaled + o.scaled} }
+func (d Decimal) Sub(o Decimal) Decimal { return Decimal{scaled: d.scaled - o.scaled} }
+
+// DivInt divides d by a small whole number, e.g. averaging a bid and ask
+// into a mid price. Truncates toward zero.
+func (d Decimal) DivInt(n int64) Decimal {
+	if n == 0 {
+		return Decimal{}
+	}
+	return Decimal{scaled: d.scaled / n}
+}
+
+// Cmp returns -1, 0, or 1 as d is less than, equal to, or greater than o.
+func (d Decimal) Cmp(o Decimal) int {
+	switch {
+	case d.scaled < o.scaled:
+		return -1
+	case d.scaled > o.scaled:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (d Decimal) IsZero() bool     { return d.scaled == 0 }
+func (d Decimal) IsPositive() bool { return d.scaled > 0 }
+func (d Decimal) IsNegative() bool { return d.scaled < 0 }
+
+// FloorToStep truncates d down to the nearest multiple of step, e.g.
+// rounding a pro-rata share down to a whole lot. A non-positive step
+// leaves d unchanged.
+func (d Decimal) FloorToStep(step Decimal) Decimal {
+	if step.scaled <= 0 {
+		return d
+	}
+	return Decimal{scaled: (d.scaled / step.scaled) * step.scaled}
+}
+
+// IsMultipleOf reports whether d is an exact multiple of step, e.g.
+// checking a price against a symbol's tick size. A non-positive step
+// imposes no restriction.
+func (d Decimal) IsMultipleOf(step Decimal) bool {
+	if step.scaled <= 0 {
+		return true
+	}
+	return d.scaled%step.scaled == 0
+}
+
+// DecimalMin returns the smaller of a and b.
+func DecimalMin(a, b Decimal) Decimal {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
+
+func (d Decimal) String() string {
+	return fmt.Sprintf("%.8f", d.Float64())
+}
+
+// MarshalJSON encodes the internal scaled integer directly, so journal and
+// snapshot files round-trip Decimals exactly instead of through a lossy
+// float64 re-parse.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.scaled)
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &d.scaled)
+}
+
+// tickSizeForPrecision returns the smallest representable increment for a
+// given number of decimal places, e.g. precision 2 -> 0.01.
+func tickSizeForPrecision(precision int) Decimal {
+	if precision < 0 {
+		precision = 0
+	}
+	if precision > 8 {
+		precision = 8
+	}
+	return Decimal{scaled: decimalScale / int64(math.Pow10(precision))}
+}
@@ -0,0 +1,129 @@
+package main
+
+import "github.com/google/btree"
+
+// PriceLevelSummary aggregates all resting orders at a single price into
+// the shape exchange market-data feeds publish: a price, the total
+// resting quantity, and how many orders make it up.
+type PriceLevelSummary struct {
+	Price         Decimal
+	TotalQuantity Decimal
+	OrderCount    int
+}
+
+// Depth is an L2 market-depth snapshot: the top price levels on each side,
+// best price first.
+type Depth struct {
+	Bids []PriceLevelSummary
+	Asks []PriceLevelSummary
+}
+
+// DepthUpdateType describes how a single price level changed.
+type DepthUpdateType string
+
+const (
+	DepthUpdateAdded   DepthUpdateType = "added"
+	DepthUpdateUpdated DepthUpdateType = "updated"
+	DepthUpdateRemoved DepthUpdateType = "removed"
+)
+
+// DepthUpdate is an incremental diff published whenever a price level is
+// added, updated, or removed. Sequence increases monotonically per book so
+// subscribers can detect gaps and resync via GetDepth.
+type DepthUpdate struct {
+	Sequence uint64
+	Side     string // "buy" or "sell"
+	Type     DepthUpdateType
+	Level    PriceLevelSummary
+}
+
+// GetDepth returns an L2 snapshot of the top levels price levels on each
+// side of the book.
+func (ob *OrderBook) GetDepth(levels int) Depth {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	var depth Depth
+	ob.BuyTree.Ascend(func(i btree.Item) bool {
+		if len(depth.Bids) >= levels {
+			return false
+		}
+		depth.Bids = append(depth.Bids, summarizeLevel(i.(PriceLevel)))
+		return true
+	})
+	ob.SellTree.Ascend(func(i btree.Item) bool {
+		if len(depth.Asks) >= levels {
+			return false
+		}
+		depth.Asks = append(depth.Asks, summarizeLevel(i.(PriceLevel)))
+		return true
+	})
+	return depth
+}
+
+// Subscribe registers ch to receive a DepthUpdate whenever AddOrder,
+// RemoveOrder, a Modify* call, or a match mutates a price level. Sends are
+// non-blocking: a subscriber that falls behind should resync from GetDepth
+// once it notices a gap in Sequence.
+func (ob *OrderBook) Subscribe(ch chan<- DepthUpdate) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.subscribers = append(ob.subscribers, ch)
+}
+
+// Unsubscribe removes ch so it no longer receives DepthUpdates. Callers
+// that Subscribe for a bounded lifetime (e.g. a watcher goroutine) must
+// Unsubscribe before they stop reading ch, or ob.subscribers leaks it and
+// every future mutation attempts a dropped send to it forever.
+func (ob *OrderBook) Unsubscribe(ch chan<- DepthUpdate) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	for i, sub := range ob.subscribers {
+		if sub == ch {
+			ob.subscribers = append(ob.subscribers[:i], ob.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+func summarizeLevel(level PriceLevel) PriceLevelSummary {
+	summary := PriceLevelSummary{Price: level.Price, OrderCount: len(level.Orders)}
+	for _, o := range level.Orders {
+		summary.TotalQuantity = summary.TotalQuantity.Add(o.Quantity)
+	}
+	return summary
+}
+
+// notifyDepth publishes a DepthUpdate for the price level at (side, price)
+// after it has already been mutated. Callers must hold ob.mu.
+func (ob *OrderBook) notifyDepth(side string, price Decimal, changeType DepthUpdateType) {
+	if len(ob.subscribers) == 0 {
+		return
+	}
+
+	summary := PriceLevelSummary{Price: price}
+	if changeType != DepthUpdateRemoved {
+		tree := ob.BuyTree
+		if side == "sell" {
+			tree = ob.SellTree
+		}
+		if item := tree.Get(PriceLevel{Price: price, Side: side}); item != nil {
+			summary = summarizeLevel(item.(PriceLevel))
+		}
+	}
+
+	ob.seq++
+	update := DepthUpdate{
+		Sequence: ob.seq,
+		Side:     side,
+		Type:     changeType,
+		Level:    summary,
+	}
+	for _, ch := range ob.subscribers {
+		select {
+		case ch <- update:
+		default:
+			// Slow subscriber; it will notice the sequence gap and resync.
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// SymbolConfig controls how a symbol's OrderBook is constructed when it is
+// registered with a MatchingEngine.
+type SymbolConfig = OrderBookConfig
+
+// TradeEvent carries a Trade alongside the symbol it occurred on, for
+// engine-wide trade fan-out.
+type TradeEvent struct {
+	Symbol string
+	Trade  Trade
+}
+
+// MatchingEngine owns one OrderBook per trading symbol. Each book is
+// processed on its own goroutine consuming from a dedicated order channel,
+// so contention on one symbol's book never blocks another's.
+type MatchingEngine struct {
+	mu      sync.RWMutex
+	books   map[string]*OrderBook
+	orderCh map[string]chan *Order
+	trades  chan TradeEvent
+	wg      sync.WaitGroup
+}
+
+// NewMatchingEngine creates an empty engine. Symbols must be registered
+// with RegisterSymbol before orders can be submitted for them.
+func NewMatchingEngine() *MatchingEngine {
+	return &MatchingEngine{
+		books:   make(map[string]*OrderBook),
+		orderCh: make(map[string]chan *Order),
+		trades:  make(chan TradeEvent, 256),
+	}
+}
+
+// RegisterSymbol creates the OrderBook for symbol (if it doesn't already
+// exist) and starts its dedicated matching goroutine.
+func (me *MatchingEngine) RegisterSymbol(symbol string, cfg SymbolConfig) *OrderBook {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	if ob, exists := me.books[symbol]; exists {
+		return ob
+	}
+
+	ob := NewOrderBook(cfg)
+	ch := make(chan *Order, 256)
+	me.books[symbol] = ob
+	me.orderCh[symbol] = ch
+
+	me.wg.Add(1)
+	go me.run(symbol, ob, ch)
+
+	return ob
+}
+
+func (me *MatchingEngine) run(symbol string, ob *OrderBook, ch chan *Order) {
+	defer me.wg.Done()
+	for order := range ch {
+		trades, _, _, err := ob.ProcessOrder(order, false)
+		if err != nil {
+			// Order rejected (e.g. a FOK that could not be fully filled).
+			continue
+		}
+		for _, t := range trades {
+			// Non-blocking: Trades() has no guaranteed consumer, and a full
+			// buffer here must never stall matching (or, transitively,
+			// Close's wg.Wait).
+			select {
+			case me.trades <- TradeEvent{Symbol: symbol, Trade: t}:
+			default:
+				log.Printf("engine: trade fan-out full, dropping trade for %s", symbol)
+			}
+		}
+	}
+}
+
+// SubmitOrder hands order to symbol's matching goroutine. It returns an
+// error if symbol has not been registered.
+func (me *MatchingEngine) SubmitOrder(symbol string, order *Order) error {
+	me.mu.RLock()
+	ch, ok := me.orderCh[symbol]
+	me.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown symbol %q", symbol)
+	}
+	ch <- order
+	return nil
+}
+
+// Book returns the OrderBook registered for symbol, if any.
+func (me *MatchingEngine) Book(symbol string) (*OrderBook, bool) {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	ob, ok := me.books[symbol]
+	return ob, ok
+}
+
+// Trades returns the engine-wide trade fan-out channel, carrying trades
+// from every registered symbol as they execute.
+func (me *MatchingEngine) Trades() <-chan TradeEvent {
+	return me.trades
+}
+
+// Close stops accepting new orders, waits for every symbol's matching
+// goroutine to drain, and closes the trade fan-out channel.
+func (me *MatchingEngine) Close() {
+	me.mu.Lock()
+	for _, ch := range me.orderCh {
+		close(ch)
+	}
+	me.mu.Unlock()
+
+	me.wg.Wait()
+	close(me.trades)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRecoverOrderBook_RoundTripsConfigFromJournal guards against the
+// regression where RecoverOrderBook always rebuilt the book with
+// NewOrderBook(OrderBookConfig{}), silently resetting a configured tick
+// size, lot size, and matching policy to their zero-value defaults.
+func TestRecoverOrderBook_RoundTripsConfigFromJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.journal")
+
+	cfg := OrderBookConfig{Policy: ProRata{}, PricePrecision: 2, QuantityPrecision: 4}
+	ob1, err := RecoverOrderBook(path, cfg)
+	if err != nil {
+		t.Fatalf("bootstrap RecoverOrderBook: %v", err)
+	}
+	if err := ob1.AddOrder(&Order{
+		OrderID:  "o1",
+		Side:     "buy",
+		Price:    NewDecimalFromFloat(10.55),
+		Quantity: NewDecimalFromFloat(1.2345),
+	}); err != nil {
+		t.Fatalf("AddOrder on bootstrapped book: %v", err)
+	}
+	if err := ob1.journal.Close(); err != nil {
+		t.Fatalf("close journal: %v", err)
+	}
+
+	// Recover again with a deliberately different default: since a config
+	// record is already journaled, the default must be ignored.
+	ob2, err := RecoverOrderBook(path, OrderBookConfig{})
+	if err != nil {
+		t.Fatalf("second RecoverOrderBook: %v", err)
+	}
+	defer ob2.journal.Close()
+
+	if _, ok := ob2.policy.(ProRata); !ok {
+		t.Fatalf("recovered policy = %T, want ProRata", ob2.policy)
+	}
+	if err := ob2.validateOrder(&Order{OrderType: OrderTypeLimit, Price: NewDecimalFromFloat(10.55), Quantity: NewDecimalFromFloat(1.2345)}); err != nil {
+		t.Fatalf("recovered book rejected a precision-2/4 order: %v", err)
+	}
+	if err := ob2.validateOrder(&Order{OrderType: OrderTypeLimit, Price: NewDecimalFromFloat(10.555), Quantity: NewDecimalFromFloat(1)}); err == nil {
+		t.Fatalf("recovered book accepted a price finer than its precision-2 tick size")
+	}
+}
+
+// TestRecoverOrderBook_RoundTripsConfigFromSnapshot guards the same
+// precision/policy persistence through Snapshot rather than a bootstrap
+// RecordConfig journal entry.
+func TestRecoverOrderBook_RoundTripsConfigFromSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.journal")
+	snapshotPath := path + ".snapshot"
+
+	cfg := OrderBookConfig{Policy: PriceTimeProRataHybrid{TopOfBookRatio: 0.3}, PricePrecision: 3, QuantityPrecision: 2}
+	ob1, err := RecoverOrderBook(path, cfg)
+	if err != nil {
+		t.Fatalf("bootstrap RecoverOrderBook: %v", err)
+	}
+	if err := ob1.Snapshot(snapshotPath); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := ob1.journal.Close(); err != nil {
+		t.Fatalf("close journal: %v", err)
+	}
+
+	ob2, err := RecoverOrderBook(path, OrderBookConfig{})
+	if err != nil {
+		t.Fatalf("second RecoverOrderBook: %v", err)
+	}
+	defer ob2.journal.Close()
+
+	hybrid, ok := ob2.policy.(PriceTimeProRataHybrid)
+	if !ok {
+		t.Fatalf("recovered policy = %T, want PriceTimeProRataHybrid", ob2.policy)
+	}
+	if hybrid.TopOfBookRatio != 0.3 {
+		t.Fatalf("recovered TopOfBookRatio = %v, want 0.3", hybrid.TopOfBookRatio)
+	}
+	if ob2.pricePrecision != 3 || ob2.quantityPrecision != 2 {
+		t.Fatalf("recovered precision = (%d, %d), want (3, 2)", ob2.pricePrecision, ob2.quantityPrecision)
+	}
+}
+
+// TestSnapshotRecover_PreservesPriceTimeOrder guards against the regression
+// where Snapshot serialized ob.Orders (a Go map) in its randomized
+// iteration order, so multiple resting orders at the same price level came
+// back from recovery in a scrambled queue, silently breaking price-time
+// priority.
+func TestSnapshotRecover_PreservesPriceTimeOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.journal")
+	snapshotPath := path + ".snapshot"
+
+	ob1, err := RecoverOrderBook(path, OrderBookConfig{})
+	if err != nil {
+		t.Fatalf("bootstrap RecoverOrderBook: %v", err)
+	}
+
+	base := time.Now()
+	ids := []string{"third", "first", "second"} // deliberately out of arrival order
+	timestamps := map[string]time.Time{
+		"first":  base,
+		"second": base.Add(time.Millisecond),
+		"third":  base.Add(2 * time.Millisecond),
+	}
+	for _, id := range ids {
+		if err := ob1.AddOrder(&Order{
+			OrderID:   id,
+			Side:      "buy",
+			Price:     NewDecimalFromFloat(10),
+			Quantity:  NewDecimalFromFloat(1),
+			Timestamp: timestamps[id],
+		}); err != nil {
+			t.Fatalf("AddOrder(%s): %v", id, err)
+		}
+	}
+	if err := ob1.Snapshot(snapshotPath); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := ob1.journal.Close(); err != nil {
+		t.Fatalf("close journal: %v", err)
+	}
+
+	ob2, err := RecoverOrderBook(path, OrderBookConfig{})
+	if err != nil {
+		t.Fatalf("RecoverOrderBook: %v", err)
+	}
+	defer ob2.journal.Close()
+
+	item := ob2.BuyTree.Get(PriceLevel{Price: NewDecimalFromFloat(10), Side: "buy"})
+	if item == nil {
+		t.Fatalf("recovered book has no price level at 10")
+	}
+	level := item.(PriceLevel)
+	gotIDs := make([]string, len(level.Orders))
+	for i, o := range level.Orders {
+		gotIDs[i] = o.OrderID
+	}
+	wantIDs := []string{"first", "second", "third"}
+	if len(gotIDs) != len(wantIDs) {
+		t.Fatalf("recovered order IDs = %v, want %v", gotIDs, wantIDs)
+	}
+	for i := range wantIDs {
+		if gotIDs[i] != wantIDs[i] {
+			t.Fatalf("recovered order IDs = %v, want %v (FIFO by Timestamp)", gotIDs, wantIDs)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Allocation is the quantity a MatchingPolicy decides to fill against a
+// single resting order at a price level.
+type Allocation struct {
+	Order    *Order
+	Quantity Decimal
+}
+
+// MatchingPolicy decides how an incoming order's quantity is distributed
+// across the resting orders at a single price level. It is invoked once per
+// price level inside the matching loop, so implementations only ever see
+// orders that already share a price.
+type MatchingPolicy interface {
+	Allocate(orders []*Order, availableQty func(*Order) Decimal, incomingQty Decimal, lotSize Decimal) []Allocation
+}
+
+// PriceTimePriority fills resting orders strictly in arrival order (FIFO),
+// the behavior the book used before matching policies were pluggable.
+type PriceTimePriority struct{}
+
+func (PriceTimePriority) Allocate(orders []*Order, availableQty func(*Order) Decimal, incomingQty Decimal, lotSize Decimal) []Allocation {
+	allocations := make([]Allocation, 0, len(orders))
+	remaining := incomingQty
+	for _, o := range orders {
+		if !remaining.IsPositive() {
+			break
+		}
+		q := availableQty(o)
+		if !q.IsPositive() {
+			continue
+		}
+		fillQty := DecimalMin(remaining, q)
+		allocations = append(allocations, Allocation{Order: o, Quantity: fillQty})
+		remaining = remaining.Sub(fillQty)
+	}
+	return allocations
+}
+
+// ProRata allocates the incoming quantity across all resting orders at the
+// price level proportionally to their size. Shares are rounded down to the
+// book's lot size, and orders whose share rounds to zero get no proportional
+// share. Any leftover from that rounding is then handed to the largest
+// resting orders first, which can include orders whose share rounded to zero.
+type ProRata struct{}
+
+func (ProRata) Allocate(orders []*Order, availableQty func(*Order) Decimal, incomingQty Decimal, lotSize Decimal) []Allocation {
+	type candidate struct {
+		order *Order
+		qty   Decimal
+	}
+
+	var candidates []candidate
+	total := 0.0
+	for _, o := range orders {
+		q := availableQty(o)
+		if !q.IsPositive() {
+			continue
+		}
+		candidates = append(candidates, candidate{o, q})
+		total += q.Float64()
+	}
+	if total <= 0 || !incomingQty.IsPositive() {
+		return nil
+	}
+
+	fillQty := incomingQty
+	totalDecimal := NewDecimalFromFloat(total)
+	if totalDecimal.Cmp(fillQty) < 0 {
+		fillQty = totalDecimal
+	}
+	fillFloat := fillQty.Float64()
+
+	shares := make(map[string]Decimal, len(candidates))
+	allocated := Decimal{}
+	for _, c := range candidates {
+		share := NewDecimalFromFloat((c.qty.Float64() / total) * fillFloat).FloorToStep(lotSize)
+		if !share.IsPositive() {
+			continue
+		}
+		shares[c.order.OrderID] = share
+		allocated = allocated.Add(share)
+	}
+
+	// Hand leftover lots (from flooring each share to the lot size) to the
+	// largest resting orders first, bounded by how much of their quantity is
+	// still unfilled.
+	leftover := fillQty.Sub(allocated).FloorToStep(lotSize)
+	if leftover.IsPositive() {
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].qty.Cmp(candidates[j].qty) > 0 })
+		for _, c := range candidates {
+			if !leftover.IsPositive() {
+				break
+			}
+			capacity := c.qty.Sub(shares[c.order.OrderID]).FloorToStep(lotSize)
+			if !capacity.IsPositive() {
+				continue
+			}
+			grant := DecimalMin(leftover, capacity)
+			shares[c.order.OrderID] = shares[c.order.OrderID].Add(grant)
+			leftover = leftover.Sub(grant)
+		}
+	}
+
+	allocations := make([]Allocation, 0, len(shares))
+	for _, o := range orders {
+		if qty, ok := shares[o.OrderID]; ok && qty.IsPositive() {
+			allocations = append(allocations, Allocation{Order: o, Quantity: qty})
+		}
+	}
+	return allocations
+}
+
+// PriceTimeProRataHybrid gives a TopOfBookRatio fraction of the incoming
+// quantity to the front of the queue FIFO, then allocates whatever remains
+// pro-rata across all resting orders' remaining capacity.
+type PriceTimeProRataHybrid struct {
+	// TopOfBookRatio is the fraction (0, 1] of the incoming quantity
+	// reserved for price-time priority before the remainder is split
+	// pro-rata. Defaults to 0.2 if left zero.
+	TopOfBookRatio float64
+}
+
+func (h PriceTimeProRataHybrid) Allocate(orders []*Order, availableQty func(*Order) Decimal, incomingQty Decimal, lotSize Decimal) []Allocation {
+	ratio := h.TopOfBookRatio
+	if ratio <= 0 {
+		ratio = 0.2
+	}
+	if ratio > 1 {
+		ratio = 1
+	}
+
+	fifoTarget := NewDecimalFromFloat(incomingQty.Float64() * ratio)
+	fifoAlloc := PriceTimePriority{}.Allocate(orders, availableQty, fifoTarget, lotSize)
+
+	filled := make(map[string]Decimal, len(fifoAlloc))
+	fifoQty := Decimal{}
+	for _, a := range fifoAlloc {
+		filled[a.Order.OrderID] = a.Quantity
+		fifoQty = fifoQty.Add(a.Quantity)
+	}
+
+	remainingIncoming := incomingQty.Sub(fifoQty)
+	if !remainingIncoming.IsPositive() {
+		return fifoAlloc
+	}
+
+	remainingAvailable := func(o *Order) Decimal {
+		return availableQty(o).Sub(filled[o.OrderID])
+	}
+
+	merged := make(map[string]Decimal, len(filled))
+	for id, qty := range filled {
+		merged[id] = qty
+	}
+	for _, a := range (ProRata{}).Allocate(orders, remainingAvailable, remainingIncoming, lotSize) {
+		merged[a.Order.OrderID] = merged[a.Order.OrderID].Add(a.Quantity)
+	}
+
+	allocations := make([]Allocation, 0, len(merged))
+	for _, o := range orders {
+		if qty, ok := merged[o.OrderID]; ok && qty.IsPositive() {
+			allocations = append(allocations, Allocation{Order: o, Quantity: qty})
+		}
+	}
+	return allocations
+}
+
+// policyName returns the stable identifier used to persist policy in a
+// snapshot or journal config record. An unrecognized implementation
+// returns "", since it has no way to be reconstructed by policyFromName.
+func policyName(policy MatchingPolicy) string {
+	switch p := policy.(type) {
+	case PriceTimePriority:
+		return "price_time"
+	case ProRata:
+		return "pro_rata"
+	case PriceTimeProRataHybrid:
+		return fmt.Sprintf("hybrid:%g", p.TopOfBookRatio)
+	default:
+		return ""
+	}
+}
+
+// policyFromName reconstructs the MatchingPolicy identified by name, as
+// produced by policyName. An unrecognized or empty name falls back to
+// PriceTimePriority, the OrderBook default.
+func policyFromName(name string) MatchingPolicy {
+	switch {
+	case name == "pro_rata":
+		return ProRata{}
+	case strings.HasPrefix(name, "hybrid:"):
+		var ratio float64
+		fmt.Sscanf(strings.TrimPrefix(name, "hybrid:"), "%g", &ratio)
+		return PriceTimeProRataHybrid{TopOfBookRatio: ratio}
+	default:
+		return PriceTimePriority{}
+	}
+}
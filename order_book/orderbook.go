@@ -0,0 +1,551 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/btree"
+)
+
+// OrderType identifies how an incoming order should be matched against the book.
+type OrderType string
+
+const (
+	// OrderTypeLimit rests on the book at its limit price if it cannot be
+	// filled immediately.
+	OrderTypeLimit OrderType = "limit"
+	// OrderTypeMarket ignores Price and sweeps the opposite book until it is
+	// fully filled or the book is exhausted. Any unfilled remainder is
+	// cancelled rather than rested.
+	OrderTypeMarket OrderType = "market"
+	// OrderTypeIOC (immediate-or-cancel) fills whatever it can immediately
+	// and cancels the rest instead of resting it on the book.
+	OrderTypeIOC OrderType = "ioc"
+	// OrderTypeFOK (fill-or-kill) only executes if the order can be filled
+	// in its entirety; otherwise nothing is matched.
+	OrderTypeFOK OrderType = "fok"
+)
+
+type Order struct {
+	OrderID   string
+	Price     Decimal
+	Quantity  Decimal
+	Timestamp time.Time
+	Side      string // "buy" or "sell"
+	OrderType OrderType
+}
+
+// Trade is a single execution between a resting and an incoming order.
+type Trade struct {
+	BuyOrderID  string
+	SellOrderID string
+	Price       Decimal
+	Quantity    Decimal
+	Timestamp   time.Time
+}
+
+type PriceLevel struct {
+	Price  Decimal
+	Orders []*Order
+	Side   string // "buy" or "sell"
+}
+
+func (p PriceLevel) Less(than btree.Item) bool {
+	o := than.(PriceLevel)
+	if p.Side == "buy" {
+		// For buy orders, higher prices come first.
+		return p.Price.Cmp(o.Price) > 0
+	} else {
+		// For sell orders, lower prices come first.
+		return p.Price.Cmp(o.Price) < 0
+	}
+}
+
+// OrderBookConfig controls how a new OrderBook fills resting orders and
+// which prices/quantities it accepts.
+type OrderBookConfig struct {
+	// Policy selects the allocation strategy used at each price level. A
+	// nil Policy defaults to PriceTimePriority.
+	Policy MatchingPolicy
+	// PricePrecision and QuantityPrecision are the number of decimal
+	// places AddOrder/ProcessOrder accept for Price and Quantity,
+	// respectively. Both default to 0 (whole numbers only) when unset, so
+	// a tick size and lot size of 1 are enforced unless configured
+	// otherwise.
+	PricePrecision    int
+	QuantityPrecision int
+}
+
+// OrderBook is safe for concurrent use; all tree and map access is guarded
+// by mu.
+type OrderBook struct {
+	mu                sync.RWMutex
+	BuyTree           *btree.BTree      // Stores PriceLevels with Side "buy"
+	SellTree          *btree.BTree      // Stores PriceLevels with Side "sell"
+	Orders            map[string]*Order // Map from OrderID to Order pointer
+	LastTradedPrice   Decimal           // Stores the last traded price
+	policy            MatchingPolicy    // Allocation strategy used at each price level
+	tickSize          Decimal           // Smallest accepted increment for Order.Price
+	lotSize           Decimal           // Smallest accepted increment for Order.Quantity
+	pricePrecision    int               // Configured OrderBookConfig.PricePrecision, kept for Config/persistence
+	quantityPrecision int               // Configured OrderBookConfig.QuantityPrecision, kept for Config/persistence
+	subscribers       []chan<- DepthUpdate
+	seq               uint64   // Monotonic sequence number for published DepthUpdates
+	journal           *Journal // Write-ahead log; nil unless opened via RecoverOrderBook
+}
+
+// journalAppend writes rec to the book's journal, if one is attached.
+// Callers must hold ob.mu.
+func (ob *OrderBook) journalAppend(rec JournalRecord) {
+	if ob.journal == nil {
+		return
+	}
+	if err := ob.journal.Append(rec); err != nil {
+		fmt.Printf("journal append failed: %v\n", err)
+	}
+}
+
+// NewOrderBook creates an OrderBook per cfg. See OrderBookConfig for
+// defaults.
+func NewOrderBook(cfg OrderBookConfig) *OrderBook {
+	policy := cfg.Policy
+	if policy == nil {
+		policy = PriceTimePriority{}
+	}
+	return &OrderBook{
+		BuyTree:           btree.New(3),
+		SellTree:          btree.New(3),
+		Orders:            make(map[string]*Order),
+		policy:            policy,
+		tickSize:          tickSizeForPrecision(cfg.PricePrecision),
+		lotSize:           tickSizeForPrecision(cfg.QuantityPrecision),
+		pricePrecision:    cfg.PricePrecision,
+		quantityPrecision: cfg.QuantityPrecision,
+	}
+}
+
+// Config returns the OrderBookConfig that reconstructs an equivalent book,
+// e.g. to persist alongside a snapshot so recovery restores the same tick
+// size, lot size, and matching policy.
+func (ob *OrderBook) Config() OrderBookConfig {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return OrderBookConfig{
+		Policy:            ob.policy,
+		PricePrecision:    ob.pricePrecision,
+		QuantityPrecision: ob.quantityPrecision,
+	}
+}
+
+// validateOrder enforces the book's tick and lot size against order. A
+// market order's Price is ignored, since ProcessOrder never matches
+// against it. Callers must hold ob.mu.
+func (ob *OrderBook) validateOrder(order *Order) error {
+	if order.OrderType != OrderTypeMarket && !order.Price.IsMultipleOf(ob.tickSize) {
+		return fmt.Errorf("price %s is not a multiple of tick size %s", order.Price, ob.tickSize)
+	}
+	if !order.Quantity.IsMultipleOf(ob.lotSize) {
+		return fmt.Errorf("quantity %s is not a multiple of lot size %s", order.Quantity, ob.lotSize)
+	}
+	return nil
+}
+
+// AddOrder rests an order on the book without attempting to match it.
+func (ob *OrderBook) AddOrder(order *Order) error {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if err := ob.validateOrder(order); err != nil {
+		return err
+	}
+
+	ob.addOrder(order)
+	ob.journalAppend(JournalRecord{Type: RecordAddOrder, Order: order})
+	return nil
+}
+
+func (ob *OrderBook) addOrder(order *Order) {
+	if order.OrderType == "" {
+		order.OrderType = OrderTypeLimit
+	}
+
+	ob.Orders[order.OrderID] = order
+
+	priceLevel := PriceLevel{
+		Price: order.Price,
+		Side:  order.Side,
+	}
+
+	var tree *btree.BTree
+	if order.Side == "buy" {
+		tree = ob.BuyTree
+	} else {
+		tree = ob.SellTree
+	}
+
+	item := tree.Get(priceLevel)
+	changeType := DepthUpdateAdded
+	if item != nil {
+		existingLevel := item.(PriceLevel)
+		existingLevel.Orders = append(existingLevel.Orders, order)
+		tree.ReplaceOrInsert(existingLevel)
+		changeType = DepthUpdateUpdated
+	} else {
+		priceLevel.Orders = []*Order{order}
+		tree.ReplaceOrInsert(priceLevel)
+	}
+	ob.notifyDepth(order.Side, order.Price, changeType)
+}
+
+func (ob *OrderBook) RemoveOrder(orderID string) error {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	order, exists := ob.Orders[orderID]
+	if !exists {
+		return fmt.Errorf("order not found")
+	}
+
+	ob.removeOrder(order)
+	delete(ob.Orders, orderID)
+	ob.journalAppend(JournalRecord{Type: RecordRemoveOrder, OrderID: orderID})
+	return nil
+}
+
+func (ob *OrderBook) removeOrder(order *Order) {
+	priceLevel := PriceLevel{
+		Price: order.Price,
+		Side:  order.Side,
+	}
+
+	var tree *btree.BTree
+	if order.Side == "buy" {
+		tree = ob.BuyTree
+	} else {
+		tree = ob.SellTree
+	}
+
+	item := tree.Get(priceLevel)
+	if item != nil {
+		existingLevel := item.(PriceLevel)
+		for idx, ord := range existingLevel.Orders {
+			if ord.OrderID == order.OrderID {
+				// Remove from list
+				existingLevel.Orders = append(existingLevel.Orders[:idx], existingLevel.Orders[idx+1:]...)
+				if len(existingLevel.Orders) == 0 {
+					tree.Delete(existingLevel)
+					ob.notifyDepth(order.Side, order.Price, DepthUpdateRemoved)
+				} else {
+					tree.ReplaceOrInsert(existingLevel)
+					ob.notifyDepth(order.Side, order.Price, DepthUpdateUpdated)
+				}
+				break
+			}
+		}
+	}
+}
+
+func (ob *OrderBook) ModifyOrderPrice(orderID string, newPrice Decimal) error {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	order, exists := ob.Orders[orderID]
+	if !exists {
+		return fmt.Errorf("order not found")
+	}
+	if !newPrice.IsMultipleOf(ob.tickSize) {
+		return fmt.Errorf("price %s is not a multiple of tick size %s", newPrice, ob.tickSize)
+	}
+
+	// Remove order from current price level
+	ob.removeOrder(order)
+
+	// Update order's price
+	order.Price = newPrice
+
+	// Re-insert the order into the appropriate tree
+	ob.addOrder(order)
+	ob.journalAppend(JournalRecord{Type: RecordModifyPrice, OrderID: orderID, NewPrice: newPrice})
+	return nil
+}
+
+func (ob *OrderBook) ModifyOrderSide(orderID string, newSide string) error {
+	if newSide != "buy" && newSide != "sell" {
+		return fmt.Errorf("invalid side")
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	order, exists := ob.Orders[orderID]
+	if !exists {
+		return fmt.Errorf("order not found")
+	}
+
+	// Remove order from current side
+	ob.removeOrder(order)
+
+	// Update order's side
+	order.Side = newSide
+
+	// Re-insert the order into the appropriate tree
+	ob.addOrder(order)
+	ob.journalAppend(JournalRecord{Type: RecordModifySide, OrderID: orderID, NewSide: newSide})
+	return nil
+}
+
+func (ob *OrderBook) ModifyOrderQuantity(orderID string, newQuantity Decimal) error {
+	if newQuantity.IsNegative() {
+		return fmt.Errorf("invalid quantity")
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	order, exists := ob.Orders[orderID]
+	if !exists {
+		return fmt.Errorf("order not found")
+	}
+
+	// Remove order if quantity is zero
+	if newQuantity.IsZero() {
+		ob.removeOrder(order)
+		delete(ob.Orders, orderID)
+		ob.journalAppend(JournalRecord{Type: RecordRemoveOrder, OrderID: orderID})
+		return nil
+	}
+
+	if !newQuantity.IsMultipleOf(ob.lotSize) {
+		return fmt.Errorf("quantity %s is not a multiple of lot size %s", newQuantity, ob.lotSize)
+	}
+
+	// Update quantity
+	order.Quantity = newQuantity
+	// No need to re-insert since the order's position doesn't change
+	ob.notifyDepth(order.Side, order.Price, DepthUpdateUpdated)
+	ob.journalAppend(JournalRecord{Type: RecordModifyQty, OrderID: orderID, NewQty: newQuantity})
+	return nil
+}
+
+// ProcessOrder matches order against the opposite side of the book,
+// honoring its OrderType, and returns the trades that were executed plus
+// any unfilled remainder. rested reports whether that remainder was placed
+// on the book (true, limit orders only) or left unfilled and cancelled
+// (false, market/IOC/FOK orders and dry runs) — remainder is non-nil in
+// both cases, so callers can always see how much quantity went unfilled.
+// If dryRun is true, no trees or orders are mutated: ProcessOrder only
+// reports what would happen, which is used for pre-trade risk checks and
+// matching-engine simulation.
+func (ob *OrderBook) ProcessOrder(order *Order, dryRun bool) (trades []Trade, remainder *Order, rested bool, err error) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if order.OrderType == "" {
+		order.OrderType = OrderTypeLimit
+	}
+	if err := ob.validateOrder(order); err != nil {
+		return nil, order, false, err
+	}
+
+	if order.OrderType == OrderTypeFOK {
+		_, fokRemainder, err := ob.walkAndMatch(order, true)
+		if err != nil {
+			return nil, order, false, err
+		}
+		if fokRemainder != nil {
+			return nil, order, false, fmt.Errorf("fill-or-kill order %s could not be fully filled", order.OrderID)
+		}
+	}
+
+	trades, remainder, err = ob.walkAndMatch(order, dryRun)
+	if err != nil {
+		return nil, order, false, err
+	}
+
+	if dryRun || remainder == nil {
+		return trades, remainder, false, nil
+	}
+
+	if order.OrderType == OrderTypeLimit {
+		ob.addOrder(remainder)
+		ob.journalAppend(JournalRecord{Type: RecordAddOrder, Order: remainder})
+		return trades, remainder, true, nil
+	}
+
+	// Market, IOC and FOK remainders are cancelled rather than rested, but
+	// are still returned so the caller can see how much went unfilled.
+	return trades, remainder, false, nil
+}
+
+// walkAndMatch walks the opposite tree from the best price, filling across
+// price levels until order is exhausted or no crossing prices remain. When
+// dryRun is true the trees and resting orders are left untouched; simulated
+// fills are tracked locally instead.
+func (ob *OrderBook) walkAndMatch(order *Order, dryRun bool) ([]Trade, *Order, error) {
+	oppositeTree := ob.SellTree
+	if order.Side == "sell" {
+		oppositeTree = ob.BuyTree
+	}
+
+	var levels []PriceLevel
+	oppositeTree.Ascend(func(i btree.Item) bool {
+		levels = append(levels, i.(PriceLevel))
+		return true
+	})
+
+	trades := make([]Trade, 0)
+	remainingQty := order.Quantity
+	simulatedQty := make(map[string]Decimal)
+
+	for _, level := range levels {
+		if !remainingQty.IsPositive() {
+			break
+		}
+
+		if order.OrderType != OrderTypeMarket {
+			if order.Side == "buy" && order.Price.Cmp(level.Price) < 0 {
+				break
+			}
+			if order.Side == "sell" && order.Price.Cmp(level.Price) > 0 {
+				break
+			}
+		}
+
+		availableQty := func(o *Order) Decimal {
+			if q, ok := simulatedQty[o.OrderID]; ok {
+				return q
+			}
+			return o.Quantity
+		}
+
+		for _, alloc := range ob.policy.Allocate(level.Orders, availableQty, remainingQty, ob.lotSize) {
+			tradeQty := DecimalMin(alloc.Quantity, remainingQty)
+			if !tradeQty.IsPositive() {
+				continue
+			}
+			resting := alloc.Order
+
+			buyOrderID, sellOrderID := order.OrderID, resting.OrderID
+			if order.Side == "sell" {
+				buyOrderID, sellOrderID = resting.OrderID, order.OrderID
+			}
+
+			trade := Trade{
+				BuyOrderID:  buyOrderID,
+				SellOrderID: sellOrderID,
+				Price:       resting.Price,
+				Quantity:    tradeQty,
+				Timestamp:   time.Now(),
+			}
+			trades = append(trades, trade)
+
+			remainingQty = remainingQty.Sub(tradeQty)
+			newQty := availableQty(resting).Sub(tradeQty)
+
+			if dryRun {
+				simulatedQty[resting.OrderID] = newQty
+				continue
+			}
+
+			ob.LastTradedPrice = resting.Price
+			resting.Quantity = newQty
+			ob.journalAppend(JournalRecord{Type: RecordTrade, Trade: &trade})
+			if newQty.IsZero() {
+				ob.removeOrder(resting)
+				delete(ob.Orders, resting.OrderID)
+				ob.journalAppend(JournalRecord{Type: RecordRemoveOrder, OrderID: resting.OrderID})
+			} else {
+				ob.notifyDepth(resting.Side, resting.Price, DepthUpdateUpdated)
+				ob.journalAppend(JournalRecord{Type: RecordModifyQty, OrderID: resting.OrderID, NewQty: newQty})
+			}
+		}
+	}
+
+	var remainder *Order
+	if remainingQty.IsPositive() {
+		remainder = &Order{
+			OrderID:   order.OrderID,
+			Price:     order.Price,
+			Quantity:  remainingQty,
+			Timestamp: order.Timestamp,
+			Side:      order.Side,
+			OrderType: order.OrderType,
+		}
+	}
+
+	return trades, remainder, nil
+}
+
+func (ob *OrderBook) GetBestBid() (Decimal, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	if ob.BuyTree.Len() == 0 {
+		return Decimal{}, false // No bids available
+	}
+	item := ob.BuyTree.Min()
+	bestBidItem := item.(PriceLevel)
+	return bestBidItem.Price, true
+}
+
+func (ob *OrderBook) GetBestAsk() (Decimal, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	if ob.SellTree.Len() == 0 {
+		return Decimal{}, false // No asks available
+	}
+	item := ob.SellTree.Min()
+	bestAskItem := item.(PriceLevel)
+	return bestAskItem.Price, true
+}
+
+func (ob *OrderBook) GetMidPrice() (Decimal, bool) {
+	bestBid, hasBid := ob.GetBestBid()
+	bestAsk, hasAsk := ob.GetBestAsk()
+	if hasBid && hasAsk {
+		return bestBid.Add(bestAsk).DivInt(2), true
+	}
+	return Decimal{}, false // Cannot calculate mid-price if either side is empty
+}
+
+func (ob *OrderBook) GetCurrentMarketPrice() (Decimal, bool) {
+	ob.mu.RLock()
+	lastTraded := ob.LastTradedPrice
+	ob.mu.RUnlock()
+
+	if !lastTraded.IsZero() {
+		return lastTraded, true
+	}
+	midPrice, ok := ob.GetMidPrice()
+	if ok {
+		return midPrice, true
+	}
+	// If no trades have occurred and mid-price cannot be calculated
+	return Decimal{}, false
+}
+
+func (ob *OrderBook) DisplayOrderBook() {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	fmt.Println("Order Book:")
+	fmt.Println("Buy Orders:")
+	ob.BuyTree.Ascend(func(i btree.Item) bool {
+		item := i.(PriceLevel)
+		for _, ord := range item.Orders {
+			fmt.Printf("OrderID: %s, Price: %.2f, Quantity: %.2f\n", ord.OrderID, ord.Price.Float64(), ord.Quantity.Float64())
+		}
+		return true
+	})
+	fmt.Println("Sell Orders:")
+	ob.SellTree.Ascend(func(i btree.Item) bool {
+		item := i.(PriceLevel)
+		for _, ord := range item.Orders {
+			fmt.Printf("OrderID: %s, Price: %.2f, Quantity: %.2f\n", ord.OrderID, ord.Price.Float64(), ord.Quantity.Float64())
+		}
+		return true
+	})
+	fmt.Println("------------------------------")
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+// TestProcessOrder_ReportsUnrestedRemainder guards against the regression
+// where ProcessOrder's doc comment promised "the trades that were executed
+// plus any unfilled remainder" while the implementation silently dropped
+// that remainder to nil for market/IOC/FOK orders. A partially-filled IOC
+// order must still report its unfilled quantity, just flagged as not
+// resting on the book.
+func TestProcessOrder_ReportsUnrestedRemainder(t *testing.T) {
+	ob := NewOrderBook(OrderBookConfig{})
+	if err := ob.AddOrder(&Order{
+		OrderID:  "resting-sell",
+		Side:     "sell",
+		Price:    NewDecimalFromFloat(100),
+		Quantity: NewDecimalFromFloat(4),
+	}); err != nil {
+		t.Fatalf("AddOrder: %v", err)
+	}
+
+	trades, remainder, rested, err := ob.ProcessOrder(&Order{
+		OrderID:   "ioc-buy",
+		Side:      "buy",
+		Price:     NewDecimalFromFloat(100),
+		Quantity:  NewDecimalFromFloat(10),
+		OrderType: OrderTypeIOC,
+	}, false)
+	if err != nil {
+		t.Fatalf("ProcessOrder: %v", err)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("len(trades) = %d, want 1", len(trades))
+	}
+	if rested {
+		t.Fatalf("rested = true, want false for an IOC order")
+	}
+	if remainder == nil {
+		t.Fatalf("remainder = nil, want the 6 unfilled units reported")
+	}
+	if want := NewDecimalFromFloat(6); remainder.Quantity.Cmp(want) != 0 {
+		t.Fatalf("remainder.Quantity = %s, want %s", remainder.Quantity, want)
+	}
+	if _, ok := ob.Orders["ioc-buy"]; ok {
+		t.Fatalf("IOC remainder was rested on the book")
+	}
+}